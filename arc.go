@@ -0,0 +1,701 @@
+package gcache
+
+import (
+	"container/list"
+	"io"
+	"runtime"
+	"time"
+)
+
+// ARC is a constant-space adaptive replacement cache. It keeps two resident
+// LRU lists, T1 (recency) and T2 (frequency), and two ghost lists of
+// recently-evicted keys, B1 and B2, which it uses to adapt the target size
+// `p` of T1 to the workload. See Megiddo & Modha, "ARC: A Self-Tuning, Low
+// Overhead Replacement Cache".
+//
+// ARC is a thin wrapper around arcCache so that WithJanitor's finalizer
+// safety net works: the janitor goroutine holds a method value bound to the
+// arcCache, keeping it reachable for as long as the goroutine runs, so a
+// finalizer set on the arcCache itself would never fire. ARC holds no
+// reference the goroutine shares, so it becomes collectible as soon as the
+// caller drops it, even with the janitor still running.
+type ARC struct {
+	*arcCache
+}
+
+type arcCache struct {
+	baseCache
+	p int
+
+	t1      *list.List
+	t1Items map[interface{}]*list.Element
+	t2      *list.List
+	t2Items map[interface{}]*list.Element
+
+	b1      *list.List
+	b1Items map[interface{}]*list.Element
+	b2      *list.List
+	b2Items map[interface{}]*list.Element
+
+	janitor *janitor
+
+	// loadErr holds the error, if any, returned by the CacheBuilder.LoadFrom
+	// snapshot load newARC performs during construction. Build has no error
+	// return, so LoadErr is the only way a caller can detect a corrupt
+	// snapshot or failing Reader at construction time.
+	loadErr error
+}
+
+type arcItem struct {
+	clock      Clock
+	key        interface{}
+	value      interface{}
+	expiration *time.Time
+	element    *list.Element
+	// ttl is the (possibly jittered) duration expiration was computed from,
+	// kept so refresh-ahead can tell how close to expiry the item is.
+	ttl *time.Duration
+}
+
+// IsExpired returns boolean value whether this item is expired or not.
+func (it *arcItem) IsExpired(now *time.Time) bool {
+	if it.expiration == nil {
+		return false
+	}
+	if now == nil {
+		t := it.clock.Now()
+		now = &t
+	}
+	return it.expiration.Before(*now)
+}
+
+func newARC(cb *CacheBuilder) *ARC {
+	c := &arcCache{}
+	buildCache(&c.baseCache, cb)
+
+	c.init()
+	c.loadGroup.cache = c
+
+	wrapper := &ARC{c}
+	if c.janitor = startJanitor(cb, c.reapExpired); c.janitor != nil {
+		runtime.SetFinalizer(wrapper, func(w *ARC) { w.janitor.Stop() })
+	}
+	if cb.loadFrom != nil {
+		c.loadErr = c.Load(cb.loadFrom)
+	}
+	return wrapper
+}
+
+// reapExpired removes every resident T1/T2 item whose TTL has passed as of
+// now. Ghost entries carry no expiration and are left alone.
+func (c *arcCache) reapExpired(now time.Time) {
+	c.mu.Lock()
+	var expired []*arcItem
+	for _, ele := range c.t1Items {
+		if item := ele.Value.(*arcItem); item.IsExpired(&now) {
+			expired = append(expired, item)
+		}
+	}
+	for _, ele := range c.t2Items {
+		if item := ele.Value.(*arcItem); item.IsExpired(&now) {
+			expired = append(expired, item)
+		}
+	}
+	for _, item := range expired {
+		if ele, ok := c.t1Items[item.key]; ok {
+			c.t1.Remove(ele)
+			delete(c.t1Items, item.key)
+		} else if ele, ok := c.t2Items[item.key]; ok {
+			c.t2.Remove(ele)
+			delete(c.t2Items, item.key)
+		}
+		c.fireEvicted(item.key, item.value, EvictionExpired)
+		c.publishInvalidation(item.key, InvalidationExpired)
+	}
+	c.mu.Unlock()
+}
+
+// Close stops the background janitor started by CacheBuilder.WithJanitor, if
+// any. It is safe to call even when no janitor was configured.
+//
+// Close is defined on the ARC wrapper rather than promoted from arcCache so
+// it can clear the finalizer it was actually registered against; see ARC's
+// doc comment.
+func (w *ARC) Close() {
+	if w.janitor != nil {
+		w.janitor.Stop()
+		runtime.SetFinalizer(w, nil)
+	}
+}
+
+func (c *arcCache) init() {
+	c.p = 0
+	c.t1 = list.New()
+	c.t2 = list.New()
+	c.b1 = list.New()
+	c.b2 = list.New()
+	c.t1Items = make(map[interface{}]*list.Element, c.size+1)
+	c.t2Items = make(map[interface{}]*list.Element, c.size+1)
+	c.b1Items = make(map[interface{}]*list.Element, c.size+1)
+	c.b2Items = make(map[interface{}]*list.Element, c.size+1)
+}
+
+func (c *arcCache) set(key, value interface{}) (interface{}, error) {
+	var err error
+	if c.serializeFunc != nil {
+		value, err = c.serializeFunc(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ele, ok := c.t1Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		oldValue := item.value
+		item.value = value
+		c.t1.Remove(ele)
+		delete(c.t1Items, key)
+		item.element = c.t2.PushFront(item)
+		c.t2Items[key] = item.element
+		c.setExpiration(item)
+		c.fireEvicted(key, oldValue, EvictionReplaced)
+		return c.inserted(key, value, item)
+	}
+
+	if ele, ok := c.t2Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		oldValue := item.value
+		item.value = value
+		c.t2.MoveToFront(ele)
+		c.setExpiration(item)
+		c.fireEvicted(key, oldValue, EvictionReplaced)
+		return c.inserted(key, value, item)
+	}
+
+	if ele, ok := c.b1Items[key]; ok {
+		b1Len, b2Len := c.b1.Len(), c.b2.Len()
+		delta := 1
+		if b2Len > b1Len {
+			delta = b2Len / b1Len
+		}
+		c.p = min(c.size, c.p+delta)
+		c.replace(false)
+		c.b1.Remove(ele)
+		delete(c.b1Items, key)
+
+		item := &arcItem{clock: c.clock, key: key, value: value}
+		item.element = c.t2.PushFront(item)
+		c.t2Items[key] = item.element
+		c.setExpiration(item)
+		return c.inserted(key, value, item)
+	}
+
+	if ele, ok := c.b2Items[key]; ok {
+		b1Len, b2Len := c.b1.Len(), c.b2.Len()
+		delta := 1
+		if b1Len > b2Len {
+			delta = b1Len / b2Len
+		}
+		c.p = max(0, c.p-delta)
+		c.replace(true)
+		c.b2.Remove(ele)
+		delete(c.b2Items, key)
+
+		item := &arcItem{clock: c.clock, key: key, value: value}
+		item.element = c.t2.PushFront(item)
+		c.t2Items[key] = item.element
+		c.setExpiration(item)
+		return c.inserted(key, value, item)
+	}
+
+	// Brand new key.
+	if c.t1.Len()+c.b1.Len() == c.size {
+		if c.t1.Len() < c.size {
+			c.evictGhost(c.b1, c.b1Items)
+			c.replace(false)
+		} else {
+			c.evictFrom(c.t1, c.t1Items)
+		}
+	} else {
+		total := c.t1.Len() + c.t2.Len() + c.b1.Len() + c.b2.Len()
+		if total >= c.size {
+			if total == 2*c.size {
+				c.evictGhost(c.b2, c.b2Items)
+			}
+			c.replace(false)
+		}
+	}
+
+	item := &arcItem{clock: c.clock, key: key, value: value}
+	item.element = c.t1.PushFront(item)
+	c.t1Items[key] = item.element
+	c.setExpiration(item)
+	return c.inserted(key, value, item)
+}
+
+func (c *arcCache) inserted(key, value interface{}, item *arcItem) (interface{}, error) {
+	if c.addedFunc != nil {
+		c.addedFunc(key, value)
+	}
+	c.fireInsertion(key, value)
+	return item, nil
+}
+
+// setExpiration stamps item with an expiration time jittered per
+// WithExpiryJitter, recording the jittered duration as item.ttl so
+// maybeRefreshAhead can later tell how close to expiry the item is.
+func (c *arcCache) setExpiration(item *arcItem) {
+	if c.expiration != nil {
+		d := c.jitteredDuration(*c.expiration)
+		t := c.clock.Now().Add(d)
+		item.expiration = &t
+		item.ttl = &d
+	}
+}
+
+// maybeRefreshAhead kicks off an asynchronous singleflight reload for key
+// when WithRefreshAhead is configured and item's remaining TTL has dropped
+// below the configured fraction of its original ttl. The reload swaps the
+// value in place via set rather than evicting, so concurrent readers keep
+// serving the stale value until it completes.
+func (c *arcCache) maybeRefreshAhead(key interface{}, item *arcItem) {
+	if c.refreshAheadFraction <= 0 || c.loaderExpireFunc == nil || item.ttl == nil || item.expiration == nil {
+		return
+	}
+	remaining := item.expiration.Sub(c.clock.Now())
+	if remaining > time.Duration(float64(*item.ttl)*c.refreshAheadFraction) {
+		return
+	}
+	go c.getWithLoader(key, false)
+}
+
+// replace evicts a single resident entry from T1 or T2 into its matching
+// ghost list, choosing T1 when it has grown past the adaptive target `p`.
+func (c *arcCache) replace(b2ContainsKey bool) {
+	t1Len := c.t1.Len()
+	if t1Len > 0 && (t1Len > c.p || (t1Len == c.p && b2ContainsKey)) {
+		ele := c.t1.Back()
+		if ele == nil {
+			return
+		}
+		item := ele.Value.(*arcItem)
+		c.t1.Remove(ele)
+		delete(c.t1Items, item.key)
+		ghostEle := c.b1.PushFront(item.key)
+		c.b1Items[item.key] = ghostEle
+		c.fireEvicted(item.key, item.value, EvictionCapacity)
+		return
+	}
+	ele := c.t2.Back()
+	if ele == nil {
+		return
+	}
+	item := ele.Value.(*arcItem)
+	c.t2.Remove(ele)
+	delete(c.t2Items, item.key)
+	ghostEle := c.b2.PushFront(item.key)
+	c.b2Items[item.key] = ghostEle
+	c.fireEvicted(item.key, item.value, EvictionCapacity)
+}
+
+func (c *arcCache) evictFrom(l *list.List, items map[interface{}]*list.Element) {
+	ele := l.Back()
+	if ele == nil {
+		return
+	}
+	item := ele.Value.(*arcItem)
+	l.Remove(ele)
+	delete(items, item.key)
+	c.fireEvicted(item.key, item.value, EvictionCapacity)
+}
+
+func (c *arcCache) evictGhost(l *list.List, items map[interface{}]*list.Element) {
+	ele := l.Back()
+	if ele == nil {
+		return
+	}
+	l.Remove(ele)
+	delete(items, ele.Value)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Set a new key-value pair
+func (c *arcCache) Set(key, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.set(key, value)
+	return err
+}
+
+// SetWithExpire sets a new key-value pair with an expiration time
+func (c *arcCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, err := c.set(key, value)
+	if err != nil {
+		return err
+	}
+	d := c.jitteredDuration(expiration)
+	t := c.clock.Now().Add(d)
+	ai := item.(*arcItem)
+	ai.expiration = &t
+	ai.ttl = &d
+	return nil
+}
+
+// Get a value from cache pool using key if it exists.
+// If it does not exists key and has LoaderFunc,
+// generate a value using `LoaderFunc` method returns value.
+func (c *arcCache) Get(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == KeyNotFoundError {
+		return c.getWithLoader(key, true)
+	}
+	return v, err
+}
+
+// GetIFPresent gets a value from cache pool using key if it exists.
+// If it does not exists key, returns KeyNotFoundError.
+// And send a request which refresh value for specified key if cache object has LoaderFunc.
+func (c *arcCache) GetIFPresent(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == KeyNotFoundError {
+		return c.getWithLoader(key, false)
+	}
+	return v, err
+}
+
+func (c *arcCache) get(key interface{}, onLoad bool) (interface{}, error) {
+	v, err := c.getValue(key, onLoad)
+	if err != nil {
+		return nil, err
+	}
+	if c.deserializeFunc != nil {
+		return c.deserializeFunc(key, v)
+	}
+	return v, nil
+}
+
+func (c *arcCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
+	c.mu.Lock()
+	if ele, ok := c.t1Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		if item.IsExpired(nil) {
+			c.t1.Remove(ele)
+			delete(c.t1Items, key)
+			c.fireEvicted(item.key, item.value, EvictionExpired)
+			c.publishInvalidation(item.key, InvalidationExpired)
+			c.mu.Unlock()
+			if !onLoad {
+				c.statsAccessor.IncrMissCount()
+			}
+			return nil, KeyNotFoundError
+		}
+		c.t1.Remove(ele)
+		delete(c.t1Items, key)
+		item.element = c.t2.PushFront(item)
+		c.t2Items[key] = item.element
+		v := item.value
+		c.maybeRefreshAhead(key, item)
+		c.mu.Unlock()
+		if !onLoad {
+			c.statsAccessor.IncrHitCount()
+		}
+		return v, nil
+	}
+
+	if ele, ok := c.t2Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		if item.IsExpired(nil) {
+			c.t2.Remove(ele)
+			delete(c.t2Items, key)
+			c.fireEvicted(item.key, item.value, EvictionExpired)
+			c.publishInvalidation(item.key, InvalidationExpired)
+			c.mu.Unlock()
+			if !onLoad {
+				c.statsAccessor.IncrMissCount()
+			}
+			return nil, KeyNotFoundError
+		}
+		c.t2.MoveToFront(ele)
+		v := item.value
+		c.maybeRefreshAhead(key, item)
+		c.mu.Unlock()
+		if !onLoad {
+			c.statsAccessor.IncrHitCount()
+		}
+		return v, nil
+	}
+
+	c.mu.Unlock()
+	if !onLoad {
+		c.statsAccessor.IncrMissCount()
+	}
+	return nil, KeyNotFoundError
+}
+
+func (c *arcCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
+	if c.loaderExpireFunc == nil {
+		return nil, KeyNotFoundError
+	}
+	value, _, err := c.load(key, func(v interface{}, expiration *time.Duration, e error) (interface{}, error) {
+		if e != nil {
+			return nil, e
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		item, err := c.set(key, v)
+		if err != nil {
+			return nil, err
+		}
+		if expiration != nil {
+			d := c.jitteredDuration(*expiration)
+			t := c.clock.Now().Add(d)
+			ai := item.(*arcItem)
+			ai.expiration = &t
+			ai.ttl = &d
+		}
+		return v, nil
+	}, isWait)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Has checks if key exists in cache
+func (c *arcCache) Has(key interface{}) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	return c.has(key, &now)
+}
+
+func (c *arcCache) has(key interface{}, now *time.Time) bool {
+	if ele, ok := c.t1Items[key]; ok {
+		return !ele.Value.(*arcItem).IsExpired(now)
+	}
+	if ele, ok := c.t2Items[key]; ok {
+		return !ele.Value.(*arcItem).IsExpired(now)
+	}
+	return false
+}
+
+// Remove removes the provided key from the cache.
+func (c *arcCache) Remove(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ele, ok := c.t1Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		c.t1.Remove(ele)
+		delete(c.t1Items, key)
+		c.fireEvicted(item.key, item.value, EvictionManual)
+		c.publishInvalidation(key, InvalidationRemove)
+		return true
+	}
+	if ele, ok := c.t2Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		c.t2.Remove(ele)
+		delete(c.t2Items, key)
+		c.fireEvicted(item.key, item.value, EvictionManual)
+		c.publishInvalidation(key, InvalidationRemove)
+		return true
+	}
+	return false
+}
+
+// Invalidate drops key from the local cache's resident lists without
+// publishing another InvalidationEvent. Use it to apply an event a cluster
+// transport delivered from a peer node.
+func (c *arcCache) Invalidate(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ele, ok := c.t1Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		c.t1.Remove(ele)
+		delete(c.t1Items, key)
+		c.fireEvicted(item.key, item.value, EvictionManual)
+		return true
+	}
+	if ele, ok := c.t2Items[key]; ok {
+		item := ele.Value.(*arcItem)
+		c.t2.Remove(ele)
+		delete(c.t2Items, key)
+		c.fireEvicted(item.key, item.value, EvictionManual)
+		return true
+	}
+	return false
+}
+
+// GetALL returns all key-value pairs of resident (T1+T2) entries in the cache.
+func (c *arcCache) GetALL(checkExpired bool) map[interface{}]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	items := make(map[interface{}]interface{}, c.t1.Len()+c.t2.Len())
+	now := time.Now()
+	for k, ele := range c.t1Items {
+		item := ele.Value.(*arcItem)
+		if !checkExpired || !item.IsExpired(&now) {
+			items[k] = item.value
+		}
+	}
+	for k, ele := range c.t2Items {
+		item := ele.Value.(*arcItem)
+		if !checkExpired || !item.IsExpired(&now) {
+			items[k] = item.value
+		}
+	}
+	return items
+}
+
+// Keys returns a slice of the resident keys in the cache.
+func (c *arcCache) Keys(checkExpired bool) []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]interface{}, 0, c.t1.Len()+c.t2.Len())
+	now := time.Now()
+	for k := range c.t1Items {
+		if !checkExpired || c.has(k, &now) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range c.t2Items {
+		if !checkExpired || c.has(k, &now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of resident items in the cache.
+func (c *arcCache) Len(checkExpired bool) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !checkExpired {
+		return c.t1.Len() + c.t2.Len()
+	}
+	var length int
+	now := time.Now()
+	for k := range c.t1Items {
+		if c.has(k, &now) {
+			length++
+		}
+	}
+	for k := range c.t2Items {
+		if c.has(k, &now) {
+			length++
+		}
+	}
+	return length
+}
+
+// Purge completely clears the cache, including the ghost lists and the
+// adaptive target `p`. Instead of walking every resident item to fire
+// purgeVisitorFunc under the lock, it swaps in fresh lists and maps, so
+// Purge latency no longer scales with cache size. If purgeVisitorFunc (or
+// OnEvictedWithReason or a WithInvalidationPublisher) is set, it is
+// dispatched over the discarded items from a background goroutine.
+func (c *arcCache) Purge() {
+	c.mu.Lock()
+	oldT1Items, oldT2Items := c.t1Items, c.t2Items
+	c.init()
+	c.mu.Unlock()
+
+	if c.purgeVisitorFunc != nil || c.evictedWithReasonFunc != nil || c.invalidationPublisher != nil {
+		go func() {
+			for k, ele := range oldT1Items {
+				item := ele.Value.(*arcItem)
+				if c.purgeVisitorFunc != nil {
+					c.purgeVisitorFunc(k, item.value)
+				}
+				if c.evictedWithReasonFunc != nil {
+					c.evictedWithReasonFunc(k, item.value, EvictionManual)
+				}
+				c.publishInvalidation(k, InvalidationPurge)
+			}
+			for k, ele := range oldT2Items {
+				item := ele.Value.(*arcItem)
+				if c.purgeVisitorFunc != nil {
+					c.purgeVisitorFunc(k, item.value)
+				}
+				if c.evictedWithReasonFunc != nil {
+					c.evictedWithReasonFunc(k, item.value, EvictionManual)
+				}
+				c.publishInvalidation(k, InvalidationPurge)
+			}
+		}()
+	}
+}
+
+// Save serializes the cache's resident (T1+T2) key/value pairs, together
+// with their expiration times, to w using encoding/gob, T1 then T2,
+// least to most recently used within each, so Load can restore that order.
+// Ghost entries (B1/B2) carry no value and are not persisted.
+func (c *arcCache) Save(w io.Writer) error {
+	c.mu.RLock()
+	entries := make([]persistedEntry, 0, c.t1.Len()+c.t2.Len())
+	for e := c.t1.Back(); e != nil; e = e.Prev() {
+		item := e.Value.(*arcItem)
+		entries = append(entries, persistedEntry{Key: item.key, Value: item.value, Expiration: item.expiration})
+	}
+	for e := c.t2.Back(); e != nil; e = e.Prev() {
+		item := e.Value.(*arcItem)
+		entries = append(entries, persistedEntry{Key: item.key, Value: item.value, Expiration: item.expiration})
+	}
+	c.mu.RUnlock()
+	return c.encodeSnapshot(w, entries)
+}
+
+// Load restores key/value pairs previously written by Save, skipping any
+// whose expiration has already passed. It does not Purge first, so loading
+// into a non-empty cache merges the two. Every restored entry enters as a
+// T1 (recency) item, same as any other brand-new key.
+func (c *arcCache) Load(r io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.decodeSnapshot(r, func(entry persistedEntry) {
+		item, err := c.set(entry.Key, entry.Value)
+		if err != nil {
+			return
+		}
+		ai := item.(*arcItem)
+		ai.expiration = entry.Expiration
+		if entry.Expiration != nil {
+			ttl := entry.Expiration.Sub(c.clock.Now())
+			ai.ttl = &ttl
+		}
+	})
+}
+
+// SaveFile writes a Save snapshot to path, replacing it atomically so a
+// concurrent reader never observes a partial file.
+func (c *arcCache) SaveFile(path string) error {
+	return saveToFile(path, c.Save)
+}
+
+// LoadFile restores a snapshot previously written by SaveFile or Save.
+func (c *arcCache) LoadFile(path string) error {
+	return loadFromFile(path, c.Load)
+}
+
+// LoadErr returns the error, if any, from the CacheBuilder.LoadFrom snapshot
+// load performed while building this cache. It is nil when LoadFrom wasn't
+// used or the load succeeded.
+func (c *arcCache) LoadErr() error {
+	return c.loadErr
+}
@@ -0,0 +1,101 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestARCGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_ARC, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestARCLength(t *testing.T) {
+	gc := buildTestLoadingCache(t, TYPE_ARC, 1000, loader)
+	gc.Get("test1")
+	gc.Get("test2")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", length, expectedLength)
+	}
+}
+
+func TestARCEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := 11
+	gc := buildTestLoadingCache(t, TYPE_ARC, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestARCGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_ARC)
+}
+
+func TestARCHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_ARC, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+// TestARCFrequentKeySurvives verifies that a key accessed twice (and thus
+// promoted into T2) outlives keys seen only once when the cache is driven
+// past capacity, which is the whole point of ARC over plain LRU.
+func TestARCFrequentKeySurvives(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_ARC, size)
+
+	frequent := "frequent"
+	if err := gc.Set(frequent, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := gc.Get(frequent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < size*4; i++ {
+		if err := gc.Set(fmt.Sprintf("once-%d", i), i); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if !gc.Has(frequent) {
+		t.Fatal("expected a twice-accessed key to survive a scan of once-seen keys")
+	}
+}
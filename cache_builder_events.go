@@ -0,0 +1,19 @@
+package gcache
+
+// OnEvictedWithReason registers f to be called whenever an entry leaves the
+// cache, passing an EvictionReason that distinguishes capacity pressure,
+// expiration, manual removal and replacement. It composes with EvictedFunc:
+// both fire, though EvictedFunc is never called for EvictionReplaced or for
+// entries dropped by Purge, to keep its pre-existing semantics.
+func (cb *CacheBuilder) OnEvictedWithReason(f EvictedWithReasonFunc) *CacheBuilder {
+	cb.evictedWithReasonFunc = f
+	return cb
+}
+
+// OnInsertion registers f to be called whenever a new key/value pair is
+// inserted, mirroring AddedFunc but tracked independently so both can be
+// registered at once.
+func (cb *CacheBuilder) OnInsertion(f InsertionFunc) *CacheBuilder {
+	cb.insertionFunc = f
+	return cb
+}
@@ -0,0 +1,20 @@
+package gcache
+
+// Name sets the cache's name, used to populate InvalidationEvent.CacheName
+// so a subscriber juggling several caches can route an incoming event to the
+// right one.
+func (cb *CacheBuilder) Name(name string) *CacheBuilder {
+	cb.name = name
+	return cb
+}
+
+// WithInvalidationPublisher registers f to be called with an
+// InvalidationEvent whenever Remove, Purge, or an expiration-driven eviction
+// drops a key, so it can be fanned out over a cluster transport (Redis
+// pub/sub, NATS, ...) to keep peer nodes' caches coherent. Peers apply an
+// incoming event via the cache's Invalidate method, which drops the local
+// copy without publishing again.
+func (cb *CacheBuilder) WithInvalidationPublisher(f InvalidationPublisher) *CacheBuilder {
+	cb.invalidationPublisher = f
+	return cb
+}
@@ -0,0 +1,12 @@
+package gcache
+
+import "time"
+
+// WithJanitor opts the built cache into a background goroutine that sweeps
+// c.items every interval, evicting anything IsExpired reports as stale
+// instead of waiting for the next Get to find it. Call Cache.Close when
+// you're done with the cache to stop the goroutine deterministically.
+func (cb *CacheBuilder) WithJanitor(interval time.Duration) *CacheBuilder {
+	cb.janitorInterval = interval
+	return cb
+}
@@ -0,0 +1,11 @@
+package gcache
+
+import "io"
+
+// LoadFrom is a shortcut for calling Build().Load(r) in one step, so a
+// service can warm a cache from a prior Save snapshot as part of
+// constructing it instead of as a separate call.
+func (cb *CacheBuilder) LoadFrom(r io.Reader) *CacheBuilder {
+	cb.loadFrom = r
+	return cb
+}
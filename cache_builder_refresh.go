@@ -0,0 +1,23 @@
+package gcache
+
+// WithRefreshAhead opts the built cache into refreshing a key asynchronously
+// once its remaining TTL drops below fraction of its original TTL, instead
+// of waiting for it to expire and serving a miss. The refresh is
+// singleflight-deduplicated against concurrent Gets for the same key and
+// swaps the value in place rather than evicting it, so readers keep getting
+// the (slightly stale) value while the reload is in flight. Requires a
+// LoaderExpireFunc to be configured; it is a no-op otherwise.
+func (cb *CacheBuilder) WithRefreshAhead(fraction float64) *CacheBuilder {
+	cb.refreshAheadFraction = fraction
+	return cb
+}
+
+// WithExpiryJitter randomizes each computed expiration by a factor in
+// [1-deviation, 1+deviation], so that many keys set together with the same
+// TTL don't all expire in the same instant and cause a thundering-herd
+// reload. deviation defaults to 0 (no jitter, backward-compatible); 0.05 is
+// a reasonable starting point for high-fanout caches.
+func (cb *CacheBuilder) WithExpiryJitter(deviation float64) *CacheBuilder {
+	cb.expiryJitter = deviation
+	return cb
+}
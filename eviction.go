@@ -0,0 +1,63 @@
+package gcache
+
+// EvictionReason records why an entry left the cache, letting callers that
+// register OnEvictedWithReason distinguish capacity pressure from expiration
+// and explicit removal instead of treating every eviction the same.
+type EvictionReason int
+
+const (
+	// EvictionCapacity is used when the tail entry is dropped by evict() to
+	// make room for a new key.
+	EvictionCapacity EvictionReason = iota
+	// EvictionExpired is used when an entry is removed because its TTL has
+	// passed, whether discovered lazily on access or by the janitor.
+	EvictionExpired
+	// EvictionManual is used when Remove or Purge drops an entry on the
+	// caller's behalf.
+	EvictionManual
+	// EvictionReplaced is used when Set overwrites an existing key.
+	EvictionReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	case EvictionManual:
+		return "manual"
+	case EvictionReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+type (
+	// EvictedWithReasonFunc is called whenever an entry leaves the cache,
+	// alongside the EvictionReason it left for.
+	EvictedWithReasonFunc func(key, value interface{}, reason EvictionReason)
+	// InsertionFunc is called whenever a new key/value pair is inserted via
+	// Set, SetWithExpire, BatchSet or a loader.
+	InsertionFunc func(key, value interface{})
+)
+
+// fireEvicted notifies the legacy EvictedFunc (skipped for EvictionReplaced,
+// which it never fired for historically) and the newer EvictedWithReasonFunc
+// (fired for every reason) that key/value left the cache.
+func (c *baseCache) fireEvicted(key, value interface{}, reason EvictionReason) {
+	if c.evictedFunc != nil && reason != EvictionReplaced {
+		c.evictedFunc(key, value)
+	}
+	if c.evictedWithReasonFunc != nil {
+		c.evictedWithReasonFunc(key, value, reason)
+	}
+}
+
+// fireInsertion notifies OnInsertion that key/value was inserted.
+func (c *baseCache) fireInsertion(key, value interface{}) {
+	if c.insertionFunc != nil {
+		c.insertionFunc(key, value)
+	}
+}
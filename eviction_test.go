@@ -0,0 +1,66 @@
+package gcache
+
+import "testing"
+
+func TestEvictedWithReason(t *testing.T) {
+	var reasons []EvictionReason
+	gc := New(2).
+		LRU().
+		OnEvictedWithReason(func(key, value interface{}, reason EvictionReason) {
+			reasons = append(reasons, reason)
+		}).
+		Build()
+
+	gc.Set("a", 1)
+	gc.Set("b", 2)
+	gc.Set("c", 3) // over capacity: evicts "a"
+	gc.Remove("b")
+
+	if len(reasons) != 2 {
+		t.Fatalf("expected 2 eviction events, got %d: %v", len(reasons), reasons)
+	}
+	if reasons[0] != EvictionCapacity {
+		t.Errorf("expected first eviction reason %v, got %v", EvictionCapacity, reasons[0])
+	}
+	if reasons[1] != EvictionManual {
+		t.Errorf("expected second eviction reason %v, got %v", EvictionManual, reasons[1])
+	}
+}
+
+// TestEvictedFuncNotFiredOnReplace pins down the backward-compatible
+// behavior fireEvicted preserves: the legacy EvictedFunc never fired when
+// Set overwrote an existing key, and that must stay true now that
+// EvictionReplaced exists.
+func TestEvictedFuncNotFiredOnReplace(t *testing.T) {
+	fired := false
+	gc := New(2).
+		LRU().
+		EvictedFunc(func(key, value interface{}) {
+			fired = true
+		}).
+		Build()
+
+	gc.Set("a", 1)
+	gc.Set("a", 2)
+
+	if fired {
+		t.Fatal("EvictedFunc should not fire when Set overwrites an existing key")
+	}
+}
+
+func TestOnInsertion(t *testing.T) {
+	var inserted []interface{}
+	gc := New(10).
+		LRU().
+		OnInsertion(func(key, value interface{}) {
+			inserted = append(inserted, key)
+		}).
+		Build()
+
+	gc.Set("a", 1)
+	gc.Set("b", 2)
+
+	if len(inserted) != 2 || inserted[0] != "a" || inserted[1] != "b" {
+		t.Fatalf("unexpected insertion events: %v", inserted)
+	}
+}
@@ -0,0 +1,51 @@
+package gcache
+
+// InvalidationOp identifies which local operation produced an
+// InvalidationEvent.
+type InvalidationOp int
+
+const (
+	// InvalidationRemove is published when Remove drops a key.
+	InvalidationRemove InvalidationOp = iota
+	// InvalidationPurge is published for every key dropped by Purge.
+	InvalidationPurge
+	// InvalidationExpired is published when a key is dropped because its
+	// TTL passed, whether discovered lazily on access or by the janitor.
+	InvalidationExpired
+)
+
+func (op InvalidationOp) String() string {
+	switch op {
+	case InvalidationRemove:
+		return "remove"
+	case InvalidationPurge:
+		return "purge"
+	case InvalidationExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// InvalidationEvent describes a key that left one node's cache through a
+// path its peers should replicate, so a cluster transport (Redis pub/sub,
+// NATS, ...) can fan it out to other nodes running the same cache.
+type InvalidationEvent struct {
+	CacheName string
+	Key       interface{}
+	Op        InvalidationOp
+}
+
+// InvalidationPublisher is called with every InvalidationEvent a cache
+// produces, via CacheBuilder.WithInvalidationPublisher.
+type InvalidationPublisher func(event InvalidationEvent)
+
+// publishInvalidation notifies invalidationPublisher, if one is configured,
+// that key left the cache for the given reason. Invalidate does not call
+// this, so applying a remote invalidation never re-publishes it and loops
+// back to the transport that delivered it.
+func (c *baseCache) publishInvalidation(key interface{}, op InvalidationOp) {
+	if c.invalidationPublisher != nil {
+		c.invalidationPublisher(InvalidationEvent{CacheName: c.name, Key: key, Op: op})
+	}
+}
@@ -0,0 +1,53 @@
+package gcache
+
+import "testing"
+
+func TestInvalidationPublisher(t *testing.T) {
+	var events []InvalidationEvent
+	gc := New(10).
+		LRU().
+		Name("mycache").
+		WithInvalidationPublisher(func(event InvalidationEvent) {
+			events = append(events, event)
+		}).
+		Build()
+
+	if err := gc.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gc.Remove("key")
+
+	if len(events) != 1 {
+		t.Fatalf("expected exactly one invalidation event, got %d: %v", len(events), events)
+	}
+	if events[0].CacheName != "mycache" || events[0].Key != "key" || events[0].Op != InvalidationRemove {
+		t.Fatalf("unexpected event: %+v", events[0])
+	}
+}
+
+// TestInvalidateDoesNotRepublish verifies that applying a remote
+// invalidation via Invalidate doesn't publish another event, which would
+// otherwise echo the invalidation back and forth between peer nodes.
+func TestInvalidateDoesNotRepublish(t *testing.T) {
+	var events []InvalidationEvent
+	gc := New(10).
+		LRU().
+		WithInvalidationPublisher(func(event InvalidationEvent) {
+			events = append(events, event)
+		}).
+		Build().(*LRUCache)
+
+	if err := gc.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !gc.Invalidate("key") {
+		t.Fatal("expected Invalidate to report the key was present")
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected Invalidate not to publish another event, got %v", events)
+	}
+	if gc.Has("key") {
+		t.Fatal("expected Invalidate to drop the local copy")
+	}
+}
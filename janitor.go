@@ -0,0 +1,58 @@
+package gcache
+
+import (
+	"sync"
+	"time"
+)
+
+// janitor periodically sweeps a cache for expired entries in the background.
+// It backs CacheBuilder.WithJanitor and is shared by every eviction policy so
+// that stale entries don't sit occupying capacity between accesses.
+type janitor struct {
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newJanitor(interval time.Duration) *janitor {
+	return &janitor{
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// run ticks every j.interval, invoking reap, until Stop is called. It uses
+// time.NewTicker rather than time.Tick, which leaks its underlying timer for
+// the lifetime of the program.
+func (j *janitor) run(reap func(now time.Time)) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			reap(now)
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop terminates the janitor goroutine. It is safe to call any number of
+// times, including concurrently (e.g. an explicit Close racing the
+// finalizer's safety-net call).
+func (j *janitor) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+}
+
+// startJanitor launches a janitor goroutine when cb was configured with
+// WithJanitor, or returns nil otherwise.
+func startJanitor(cb *CacheBuilder, reap func(now time.Time)) *janitor {
+	if cb.janitorInterval <= 0 {
+		return nil
+	}
+	j := newJanitor(cb.janitorInterval)
+	go j.run(reap)
+	return j
+}
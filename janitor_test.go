@@ -0,0 +1,37 @@
+package gcache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestJanitorReapsExpired verifies that WithJanitor proactively removes
+// expired entries in the background, without requiring an access to trigger
+// the lazy expiration check in getValue.
+func TestJanitorReapsExpired(t *testing.T) {
+	gc := New(10).
+		LRU().
+		Expiration(10 * time.Millisecond).
+		WithJanitor(5 * time.Millisecond).
+		Build().(*LRUCache)
+	defer gc.Close()
+
+	if err := gc.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if length := gc.Len(false); length != 0 {
+		t.Fatalf("expected janitor to have reaped the expired key, got %d items remaining", length)
+	}
+}
+
+// TestJanitorCloseIdempotent verifies that Close can be called more than
+// once on a cache built with WithJanitor without panicking, since the
+// runtime.SetFinalizer safety net can race an explicit Close call.
+func TestJanitorCloseIdempotent(t *testing.T) {
+	gc := New(10).LRU().WithJanitor(time.Millisecond).Build().(*LRUCache)
+	gc.Close()
+	gc.Close()
+}
@@ -2,31 +2,88 @@ package gcache
 
 import (
 	"container/list"
+	"io"
+	"runtime"
 	"time"
 )
 
-// Discards the least recently used items first.
+// LRUCache discards the least recently used items first. It is a thin
+// wrapper around lruCache so that WithJanitor's finalizer safety net works:
+// the janitor goroutine holds a method value bound to the lruCache, keeping
+// it reachable for as long as the goroutine runs, so a finalizer set on the
+// lruCache itself would never fire. LRUCache holds no reference the
+// goroutine shares, so it becomes collectible as soon as the caller drops
+// it, even with the janitor still running.
 type LRUCache struct {
+	*lruCache
+}
+
+type lruCache struct {
 	baseCache
 	items     map[interface{}]*lruItem
 	evictList *list.List
+	janitor   *janitor
+
+	// loadErr holds the error, if any, returned by the CacheBuilder.LoadFrom
+	// snapshot load newLRUCache performs during construction. Build has no
+	// error return, so LoadErr is the only way a caller can detect a corrupt
+	// snapshot or failing Reader at construction time.
+	loadErr error
 }
 
 func newLRUCache(cb *CacheBuilder) *LRUCache {
-	c := &LRUCache{}
+	c := &lruCache{}
 	buildCache(&c.baseCache, cb)
 
 	c.init()
 	c.loadGroup.cache = c
-	return c
+
+	wrapper := &LRUCache{c}
+	if c.janitor = startJanitor(cb, c.reapExpired); c.janitor != nil {
+		runtime.SetFinalizer(wrapper, func(w *LRUCache) { w.janitor.Stop() })
+	}
+	if cb.loadFrom != nil {
+		c.loadErr = c.Load(cb.loadFrom)
+	}
+	return wrapper
+}
+
+// reapExpired removes every item whose TTL has passed as of now. It is the
+// janitor's periodic sweep; Close stops the goroutine that calls it.
+func (c *lruCache) reapExpired(now time.Time) {
+	c.mu.Lock()
+	var expired []*lruItem
+	for _, item := range c.items {
+		if item.IsExpired(&now) {
+			expired = append(expired, item)
+		}
+	}
+	for _, item := range expired {
+		c.removeElement(item, EvictionExpired)
+		c.publishInvalidation(item.key, InvalidationExpired)
+	}
+	c.mu.Unlock()
+}
+
+// Close stops the background janitor started by CacheBuilder.WithJanitor, if
+// any. It is safe to call even when no janitor was configured.
+//
+// Close is defined on the LRUCache wrapper rather than promoted from
+// lruCache so it can clear the finalizer it was actually registered
+// against; see LRUCache's doc comment.
+func (w *LRUCache) Close() {
+	if w.janitor != nil {
+		w.janitor.Stop()
+		runtime.SetFinalizer(w, nil)
+	}
 }
 
-func (c *LRUCache) init() {
+func (c *lruCache) init() {
 	c.evictList = list.New()
 	c.items = make(map[interface{}]*lruItem, c.size+1)
 }
 
-func (c *LRUCache) set(key, value interface{}) (interface{}, error) {
+func (c *lruCache) set(key, value interface{}) (interface{}, error) {
 	var err error
 	if c.serializeFunc != nil {
 		value, err = c.serializeFunc(key, value)
@@ -38,8 +95,10 @@ func (c *LRUCache) set(key, value interface{}) (interface{}, error) {
 	// Check for existing item
 	item, ok := c.items[key]
 	if ok {
+		oldValue := item.value
 		c.evictList.MoveToFront(item.element)
 		item.value = value
+		c.fireEvicted(key, oldValue, EvictionReplaced)
 	} else {
 		// Verify size not exceeded
 		if c.evictList.Len() >= c.size {
@@ -57,19 +116,19 @@ func (c *LRUCache) set(key, value interface{}) (interface{}, error) {
 	}
 
 	if c.expiration != nil {
-		t := c.clock.Now().Add(*c.expiration)
-		item.expiration = &t
+		c.applyExpiration(item, *c.expiration)
 	}
 
 	if c.addedFunc != nil {
 		c.addedFunc(key, value)
 	}
+	c.fireInsertion(key, value)
 
 	return item, nil
 }
 
 // set a new key-value pair
-func (c *LRUCache) Set(key, value interface{}) error {
+func (c *lruCache) Set(key, value interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	_, err := c.set(key, value)
@@ -77,7 +136,7 @@ func (c *LRUCache) Set(key, value interface{}) error {
 }
 
 // Set a new key-value pair with an expiration time
-func (c *LRUCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
+func (c *lruCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	item, err := c.set(key, value)
@@ -85,12 +144,11 @@ func (c *LRUCache) SetWithExpire(key, value interface{}, expiration time.Duratio
 		return err
 	}
 
-	t := c.clock.Now().Add(expiration)
-	item.(*lruItem).expiration = &t
+	c.applyExpiration(item.(*lruItem), expiration)
 	return nil
 }
 
-func (c *LRUCache) BatchSet(reqs []BatchSetReq) error {
+func (c *lruCache) BatchSet(reqs []BatchSetReq) error {
 	if len(reqs) > c.size {
 		return KeyBatchSetOverCacheSize
 	}
@@ -102,8 +160,7 @@ func (c *LRUCache) BatchSet(reqs []BatchSetReq) error {
 			return err
 		}
 		if batchSetReq.GetExpiration() != nil {
-			t := c.clock.Now().Add(*batchSetReq.GetExpiration())
-			item.(*lruItem).expiration = &t
+			c.applyExpiration(item.(*lruItem), *batchSetReq.GetExpiration())
 		}
 	}
 	return nil
@@ -112,7 +169,7 @@ func (c *LRUCache) BatchSet(reqs []BatchSetReq) error {
 // Get a value from cache pool using key if it exists.
 // If it does not exists key and has LoaderFunc,
 // generate a value using `LoaderFunc` method returns value.
-func (c *LRUCache) Get(key interface{}) (interface{}, error) {
+func (c *lruCache) Get(key interface{}) (interface{}, error) {
 	v, err := c.get(key, false)
 	if err == KeyNotFoundError {
 		return c.getWithLoader(key, true)
@@ -120,7 +177,7 @@ func (c *LRUCache) Get(key interface{}) (interface{}, error) {
 	return v, err
 }
 
-func (c *LRUCache) GetKeyTTL(key interface{}) (*time.Duration, error) {
+func (c *lruCache) GetKeyTTL(key interface{}) (*time.Duration, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	item, ok := c.items[key]
@@ -137,7 +194,7 @@ func (c *LRUCache) GetKeyTTL(key interface{}) (*time.Duration, error) {
 // GetIFPresent gets a value from cache pool using key if it exists.
 // If it does not exists key, returns KeyNotFoundError.
 // And send a request which refresh value for specified key if cache object has LoaderFunc.
-func (c *LRUCache) GetIFPresent(key interface{}) (interface{}, error) {
+func (c *lruCache) GetIFPresent(key interface{}) (interface{}, error) {
 	v, err := c.get(key, false)
 	if err == KeyNotFoundError {
 		return c.getWithLoader(key, false)
@@ -145,7 +202,7 @@ func (c *LRUCache) GetIFPresent(key interface{}) (interface{}, error) {
 	return v, err
 }
 
-func (c *LRUCache) get(key interface{}, onLoad bool) (interface{}, error) {
+func (c *lruCache) get(key interface{}, onLoad bool) (interface{}, error) {
 	v, err := c.getValue(key, onLoad)
 	if err != nil {
 		return nil, err
@@ -156,20 +213,23 @@ func (c *LRUCache) get(key interface{}, onLoad bool) (interface{}, error) {
 	return v, nil
 }
 
-func (c *LRUCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
+func (c *lruCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
 	c.mu.Lock()
 	item, ok := c.items[key]
 	if ok {
 		if !item.IsExpired(nil) {
 			c.evictList.MoveToFront(item.element)
 			v := item.value
+			c.maybeRefreshAhead(key, item)
 			c.mu.Unlock()
 			if !onLoad {
 				c.statsAccessor.IncrHitCount()
 			}
 			return v, nil
+		} else {
+			c.removeElement(item, EvictionExpired)
+			c.publishInvalidation(key, InvalidationExpired)
 		}
-		c.removeElement(item)
 	}
 	c.mu.Unlock()
 	if !onLoad {
@@ -178,7 +238,7 @@ func (c *LRUCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
 	return nil, KeyNotFoundError
 }
 
-func (c *LRUCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
+func (c *lruCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
 	if c.loaderExpireFunc == nil {
 		return nil, KeyNotFoundError
 	}
@@ -193,8 +253,7 @@ func (c *LRUCache) getWithLoader(key interface{}, isWait bool) (interface{}, err
 			return nil, err
 		}
 		if expiration != nil {
-			t := c.clock.Now().Add(*expiration)
-			item.(*lruItem).expiration = &t
+			c.applyExpiration(item.(*lruItem), *expiration)
 		}
 		return v, nil
 	}, isWait)
@@ -204,28 +263,54 @@ func (c *LRUCache) getWithLoader(key interface{}, isWait bool) (interface{}, err
 	return value, nil
 }
 
+// applyExpiration stamps item with an expiration time jittered per
+// WithExpiryJitter, recording the jittered duration as item.ttl so
+// WithRefreshAhead can later tell how close to expiry the item is.
+func (c *lruCache) applyExpiration(item *lruItem, duration time.Duration) {
+	d := c.jitteredDuration(duration)
+	t := c.clock.Now().Add(d)
+	item.expiration = &t
+	item.ttl = &d
+}
+
+// maybeRefreshAhead kicks off an asynchronous singleflight reload for key
+// when WithRefreshAhead is configured and item's remaining TTL has dropped
+// below the configured fraction of its original ttl. The reload swaps the
+// value in place via set rather than evicting, so concurrent readers keep
+// serving the stale value until it completes.
+func (c *lruCache) maybeRefreshAhead(key interface{}, item *lruItem) {
+	if c.refreshAheadFraction <= 0 || c.loaderExpireFunc == nil || item.ttl == nil || item.expiration == nil {
+		return
+	}
+	remaining := item.expiration.Sub(c.clock.Now())
+	if remaining > time.Duration(float64(*item.ttl)*c.refreshAheadFraction) {
+		return
+	}
+	go c.getWithLoader(key, false)
+}
+
 // evict removes the oldest item from the cache.
-func (c *LRUCache) evict(count int) {
+func (c *lruCache) evict(count int) {
 	for i := 0; i < count; i++ {
 		ent := c.evictList.Back()
 		if ent == nil {
 			return
 		} else {
 			entry := ent.Value.(*lruItem)
-			c.removeElement(entry)
+			c.removeElement(entry, EvictionCapacity)
 		}
 	}
 }
 
 // Has checks if key exists in cache
-func (c *LRUCache) Has(key interface{}) bool {
+func (c *lruCache) Has(key interface{}) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	now := time.Now()
 	return c.has(key, &now)
 }
 
-func (c *LRUCache) has(key interface{}, now *time.Time) bool {
+func (c *lruCache) has(key interface{}, now *time.Time) bool {
 	item, ok := c.items[key]
 	if !ok {
 		return false
@@ -234,30 +319,43 @@ func (c *LRUCache) has(key interface{}, now *time.Time) bool {
 }
 
 // Remove removes the provided key from the cache.
-func (c *LRUCache) Remove(key interface{}) bool {
+func (c *lruCache) Remove(key interface{}) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	return c.remove(key)
 }
 
-func (c *LRUCache) remove(key interface{}) bool {
+func (c *lruCache) remove(key interface{}) bool {
 	if ent, ok := c.items[key]; ok {
-		c.removeElement(ent)
+		c.removeElement(ent, EvictionManual)
+		c.publishInvalidation(key, InvalidationRemove)
 		return true
 	}
 	return false
 }
 
-func (c *LRUCache) removeElement(entry *lruItem) {
+// Invalidate drops key from the local cache without publishing another
+// InvalidationEvent. Use it to apply an event a cluster transport delivered
+// from a peer node, so the two nodes don't echo the invalidation back and
+// forth forever.
+func (c *lruCache) Invalidate(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ent, ok := c.items[key]; ok {
+		c.removeElement(ent, EvictionManual)
+		return true
+	}
+	return false
+}
+
+func (c *lruCache) removeElement(entry *lruItem, reason EvictionReason) {
 	c.evictList.Remove(entry.element)
 	delete(c.items, entry.key)
-	if c.evictedFunc != nil {
-		c.evictedFunc(entry.key, entry.value)
-	}
+	c.fireEvicted(entry.key, entry.value, reason)
 }
 
-func (c *LRUCache) keys() []interface{} {
+func (c *lruCache) keys() []interface{} {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	keys := make([]interface{}, len(c.items))
@@ -270,7 +368,7 @@ func (c *LRUCache) keys() []interface{} {
 }
 
 // GetALL returns all key-value pairs in the cache.
-func (c *LRUCache) GetALL(checkExpired bool) map[interface{}]interface{} {
+func (c *lruCache) GetALL(checkExpired bool) map[interface{}]interface{} {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	items := make(map[interface{}]interface{}, len(c.items))
@@ -283,7 +381,7 @@ func (c *LRUCache) GetALL(checkExpired bool) map[interface{}]interface{} {
 	return items
 }
 
-func (c *LRUCache) BatchGet(checkExpired bool, keys []interface{}) map[interface{}]interface{} {
+func (c *lruCache) BatchGet(checkExpired bool, keys []interface{}) map[interface{}]interface{} {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	items := make(map[interface{}]interface{}, len(keys))
@@ -304,7 +402,7 @@ func (c *LRUCache) BatchGet(checkExpired bool, keys []interface{}) map[interface
 }
 
 // Keys returns a slice of the keys in the cache.
-func (c *LRUCache) Keys(checkExpired bool) []interface{} {
+func (c *lruCache) Keys(checkExpired bool) []interface{} {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	keys := make([]interface{}, 0, len(c.items))
@@ -318,7 +416,7 @@ func (c *LRUCache) Keys(checkExpired bool) []interface{} {
 }
 
 // Len returns the number of items in the cache.
-func (c *LRUCache) Len(checkExpired bool) int {
+func (c *lruCache) Len(checkExpired bool) int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	if !checkExpired {
@@ -335,17 +433,82 @@ func (c *LRUCache) Len(checkExpired bool) int {
 }
 
 // Completely clear the cache
-func (c *LRUCache) Purge() {
+// Purge completely clears the cache. Instead of walking every item to fire
+// purgeVisitorFunc under the lock, it swaps in a fresh items map and
+// evictList, so Purge latency no longer scales with cache size. If
+// purgeVisitorFunc (or OnEvictedWithReason) is set, it is dispatched over
+// the discarded map from a background goroutine.
+func (c *lruCache) Purge() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	oldItems := c.items
+	c.init()
+	c.mu.Unlock()
 
-	if c.purgeVisitorFunc != nil {
-		for key, item := range c.items {
-			c.purgeVisitorFunc(key, item.value)
-		}
+	if c.purgeVisitorFunc != nil || c.evictedWithReasonFunc != nil || c.invalidationPublisher != nil {
+		go func() {
+			for key, item := range oldItems {
+				if c.purgeVisitorFunc != nil {
+					c.purgeVisitorFunc(key, item.value)
+				}
+				if c.evictedWithReasonFunc != nil {
+					c.evictedWithReasonFunc(key, item.value, EvictionManual)
+				}
+				c.publishInvalidation(key, InvalidationPurge)
+			}
+		}()
 	}
+}
 
-	c.init()
+// Save serializes the cache's key/value pairs, together with their
+// expiration times, to w using encoding/gob, ordered from least to most
+// recently used so Load can restore that order.
+func (c *lruCache) Save(w io.Writer) error {
+	c.mu.RLock()
+	entries := make([]persistedEntry, 0, c.evictList.Len())
+	for e := c.evictList.Back(); e != nil; e = e.Prev() {
+		item := e.Value.(*lruItem)
+		entries = append(entries, persistedEntry{Key: item.key, Value: item.value, Expiration: item.expiration})
+	}
+	c.mu.RUnlock()
+	return c.encodeSnapshot(w, entries)
+}
+
+// Load restores key/value pairs previously written by Save, skipping any
+// whose expiration has already passed. It does not Purge first, so loading
+// into a non-empty cache merges the two.
+func (c *lruCache) Load(r io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.decodeSnapshot(r, func(entry persistedEntry) {
+		item, err := c.set(entry.Key, entry.Value)
+		if err != nil {
+			return
+		}
+		li := item.(*lruItem)
+		li.expiration = entry.Expiration
+		if entry.Expiration != nil {
+			ttl := entry.Expiration.Sub(c.clock.Now())
+			li.ttl = &ttl
+		}
+	})
+}
+
+// SaveFile writes a Save snapshot to path, replacing it atomically so a
+// concurrent reader never observes a partial file.
+func (c *lruCache) SaveFile(path string) error {
+	return saveToFile(path, c.Save)
+}
+
+// LoadFile restores a snapshot previously written by SaveFile or Save.
+func (c *lruCache) LoadFile(path string) error {
+	return loadFromFile(path, c.Load)
+}
+
+// LoadErr returns the error, if any, from the CacheBuilder.LoadFrom snapshot
+// load performed while building this cache. It is nil when LoadFrom wasn't
+// used or the load succeeded.
+func (c *lruCache) LoadErr() error {
+	return c.loadErr
 }
 
 type lruItem struct {
@@ -354,6 +517,9 @@ type lruItem struct {
 	value      interface{}
 	expiration *time.Time
 	element    *list.Element
+	// ttl is the (possibly jittered) duration expiration was computed from,
+	// kept so refresh-ahead can tell how close to expiry the item is.
+	ttl *time.Duration
 }
 
 // IsExpired returns boolean value whether this item is expired or not.
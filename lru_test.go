@@ -82,6 +82,50 @@ func TestLRUGetIFPresent(t *testing.T) {
 	testGetIFPresent(t, TYPE_LRU)
 }
 
+func TestLRUPurge(t *testing.T) {
+	gc := buildTestCache(t, TYPE_LRU, 10)
+	if err := gc.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gc.Purge()
+
+	if length := gc.Len(false); length != 0 {
+		t.Fatalf("expected cache to be empty after Purge, got %d items", length)
+	}
+	if _, err := gc.Get("key"); err != KeyNotFoundError {
+		t.Fatalf("expected KeyNotFoundError after Purge, got %v", err)
+	}
+}
+
+// TestLRUPurgeVisitor verifies that purgeVisitorFunc still sees every key
+// that was resident at Purge time, even though Purge dispatches it from a
+// background goroutine over the swapped-out map instead of walking it
+// synchronously under the lock.
+func TestLRUPurgeVisitor(t *testing.T) {
+	visited := make(chan interface{}, 1)
+	gc := New(10).
+		LRU().
+		PurgeVisitorFunc(func(key, value interface{}) {
+			visited <- key
+		}).
+		Build()
+
+	if err := gc.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gc.Purge()
+
+	select {
+	case key := <-visited:
+		if key != "key" {
+			t.Fatalf("unexpected key visited: %v", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for purgeVisitorFunc to fire")
+	}
+}
+
 func TestLRUHas(t *testing.T) {
 	gc := buildTestLoadingCacheWithExpiration(t, TYPE_LRU, 2, 10*time.Millisecond)
 
@@ -0,0 +1,75 @@
+package gcache
+
+import (
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// persistedEntry is the on-the-wire representation of a single cached
+// key/value pair, written in least-to-most-recently-used order so Load can
+// rebuild that order. LRUCache restores its eviction list exactly; ARC and
+// TwoQueueCache put every restored entry into their recency list (T1 or the
+// recent queue), so which list an entry was in is not kept.
+type persistedEntry struct {
+	Key        interface{}
+	Value      interface{}
+	Expiration *time.Time
+}
+
+// encodeSnapshot gob-encodes entries (oldest first) to w. It is shared by
+// every eviction policy's Save method.
+func (c *baseCache) encodeSnapshot(w io.Writer, entries []persistedEntry) error {
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// decodeSnapshot gob-decodes entries from r and hands each one, in the order
+// written, to insert - except entries whose expiration has already passed,
+// which are skipped. It is shared by every eviction policy's Load method.
+func (c *baseCache) decodeSnapshot(r io.Reader, insert func(entry persistedEntry)) error {
+	var entries []persistedEntry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+	now := c.clock.Now()
+	for _, entry := range entries {
+		if entry.Expiration != nil && entry.Expiration.Before(now) {
+			continue
+		}
+		insert(entry)
+	}
+	return nil
+}
+
+// saveToFile calls write with a temporary file under path's directory, then
+// renames it over path so a reader never observes a partially written
+// snapshot.
+func saveToFile(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gcache-snapshot-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// loadFromFile opens path and calls read with it.
+func loadFromFile(path string, read func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return read(f)
+}
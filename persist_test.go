@@ -0,0 +1,166 @@
+package gcache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLRUSaveLoadRoundTrip(t *testing.T) {
+	src := New(10).LRU().Build().(*LRUCache)
+	if err := src.Set("a", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := src.Set("b", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).LRU().Build().(*LRUCache)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]int{"a": 1, "b": 2} {
+		got, err := dst.Get(key)
+		if err != nil {
+			t.Fatalf("expected %s to survive the round-trip: %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("expected %s=%v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestLRULoadSkipsExpiredEntries(t *testing.T) {
+	src := New(10).LRU().Build().(*LRUCache)
+	if err := src.SetWithExpire("stale", "value", time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).LRU().Build().(*LRUCache)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if dst.Has("stale") {
+		t.Fatal("expected an already-expired entry not to be restored by Load")
+	}
+}
+
+func TestLRUSaveFileLoadFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/snapshot.gob"
+
+	src := New(10).LRU().Build().(*LRUCache)
+	if err := src.Set("key", "value"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := src.SaveFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).LRU().Build().(*LRUCache)
+	if err := dst.LoadFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := dst.Get("key")
+	if err != nil {
+		t.Fatalf("expected key to survive the round-trip: %v", err)
+	}
+	if got != "value" {
+		t.Fatalf("expected value=%q, got %q", "value", got)
+	}
+}
+
+// TestLoadFromErrIsSurfaced verifies that a CacheBuilder.LoadFrom snapshot
+// load failing during construction is reported through LoadErr, rather than
+// swallowed.
+func TestLoadFromErrIsSurfaced(t *testing.T) {
+	gc := New(10).
+		LRU().
+		LoadFrom(strings.NewReader("not a valid gob snapshot")).
+		Build().(*LRUCache)
+
+	if gc.LoadErr() == nil {
+		t.Fatal("expected LoadErr to report the corrupt snapshot")
+	}
+}
+
+// TestARCSaveLoadRoundTrip verifies ARC persists and restores its resident
+// entries via the same shared encodeSnapshot/decodeSnapshot helpers LRUCache
+// uses, so ARC().LoadFrom(...) doesn't silently drop the snapshot.
+func TestARCSaveLoadRoundTrip(t *testing.T) {
+	src := New(10).EvictType(TYPE_ARC).Build().(*ARC)
+	if err := src.Set("a", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := src.Set("b", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).EvictType(TYPE_ARC).Build().(*ARC)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]int{"a": 1, "b": 2} {
+		got, err := dst.Get(key)
+		if err != nil {
+			t.Fatalf("expected %s to survive the round-trip: %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("expected %s=%v, got %v", key, want, got)
+		}
+	}
+}
+
+// TestTwoQueueSaveLoadRoundTrip verifies TwoQueueCache persists and restores
+// its resident entries via the same shared helpers LRUCache uses, so
+// TwoQueue().LoadFrom(...) doesn't silently drop the snapshot.
+func TestTwoQueueSaveLoadRoundTrip(t *testing.T) {
+	src := New(10).EvictType(TYPE_2Q).Build().(*TwoQueueCache)
+	if err := src.Set("a", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := src.Set("b", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).EvictType(TYPE_2Q).Build().(*TwoQueueCache)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]int{"a": 1, "b": 2} {
+		got, err := dst.Get(key)
+		if err != nil {
+			t.Fatalf("expected %s to survive the round-trip: %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("expected %s=%v, got %v", key, want, got)
+		}
+	}
+}
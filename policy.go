@@ -0,0 +1,13 @@
+package gcache
+
+// Eviction policy identifiers accepted by CacheBuilder.EvictType, in addition
+// to TYPE_SIMPLE, TYPE_LRU and TYPE_LFU.
+const (
+	// TYPE_2Q selects TwoQueueCache, a scan-resistant policy that keeps
+	// once-seen entries in a FIFO queue until they earn promotion into the
+	// main LRU.
+	TYPE_2Q = "2q"
+	// TYPE_ARC selects ARC, an adaptive replacement cache that balances
+	// recency and frequency automatically.
+	TYPE_ARC = "arc"
+)
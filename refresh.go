@@ -0,0 +1,19 @@
+package gcache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitteredDuration multiplies d by a random factor in
+// [1-c.expiryJitter, 1+c.expiryJitter] when WithExpiryJitter was configured,
+// so that a batch of keys set together with the same TTL don't all expire at
+// exactly the same instant and thunder the loader. It returns d unchanged
+// when expiryJitter is the zero value (the default, backward-compatible).
+func (c *baseCache) jitteredDuration(d time.Duration) time.Duration {
+	if c.expiryJitter <= 0 {
+		return d
+	}
+	factor := 1 - c.expiryJitter + rand.Float64()*2*c.expiryJitter
+	return time.Duration(float64(d) * factor)
+}
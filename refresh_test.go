@@ -0,0 +1,169 @@
+package gcache
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRefreshAhead(t *testing.T) {
+	var loads int32
+	gc := New(10).
+		LRU().
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			n := atomic.AddInt32(&loads, 1)
+			d := 50 * time.Millisecond
+			return n, &d, nil
+		}).
+		WithRefreshAhead(0.5).
+		Build()
+
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("expected exactly one initial load, got %d", n)
+	}
+
+	time.Sleep(30 * time.Millisecond) // past the 50% refresh-ahead threshold
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the async refresh complete
+	if n := atomic.LoadInt32(&loads); n < 2 {
+		t.Fatalf("expected WithRefreshAhead to trigger a second load, got %d", n)
+	}
+}
+
+func TestWithExpiryJitter(t *testing.T) {
+	gc := New(10).
+		LRU().
+		Expiration(time.Second).
+		WithExpiryJitter(0.5).
+		Build()
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := gc.Set(key, i); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ttl, err := gc.GetKeyTTL(key)
+		if err != nil || ttl == nil {
+			t.Fatalf("unexpected ttl lookup result: %v, %v", ttl, err)
+		}
+		seen[ttl.Round(100*time.Millisecond)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("expected WithExpiryJitter to vary expiration durations across keys")
+	}
+}
+
+// TestRefreshAheadSurvivesLoad verifies that an entry restored via Load gets
+// a TTL (the time left until its persisted expiration at load time), so
+// WithRefreshAhead remains eligible for it instead of silently losing
+// refresh-ahead eligibility across a redeploy-triggered warm start.
+func TestRefreshAheadSurvivesLoad(t *testing.T) {
+	var loads int32
+	loaderExpire := func(key interface{}) (interface{}, *time.Duration, error) {
+		n := atomic.AddInt32(&loads, 1)
+		d := 50 * time.Millisecond
+		return n, &d, nil
+	}
+
+	src := New(10).LRU().Build().(*LRUCache)
+	if err := src.SetWithExpire("key", "value", 50*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dst := New(10).
+		LRU().
+		LoaderExpireFunc(loaderExpire).
+		WithRefreshAhead(0.5).
+		Build().(*LRUCache)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond) // past the 50% refresh-ahead threshold
+	if _, err := dst.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the async refresh complete
+	if n := atomic.LoadInt32(&loads); n < 1 {
+		t.Fatal("expected a key restored via Load to remain eligible for refresh-ahead")
+	}
+}
+
+// TestARCWithRefreshAhead verifies WithRefreshAhead, previously
+// LRUCache-only, also takes effect for ARC.
+func TestARCWithRefreshAhead(t *testing.T) {
+	var loads int32
+	gc := New(10).
+		EvictType(TYPE_ARC).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			n := atomic.AddInt32(&loads, 1)
+			d := 50 * time.Millisecond
+			return n, &d, nil
+		}).
+		WithRefreshAhead(0.5).
+		Build()
+
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("expected exactly one initial load, got %d", n)
+	}
+
+	time.Sleep(30 * time.Millisecond) // past the 50% refresh-ahead threshold
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the async refresh complete
+	if n := atomic.LoadInt32(&loads); n < 2 {
+		t.Fatalf("expected WithRefreshAhead to trigger a second load, got %d", n)
+	}
+}
+
+// TestTwoQueueWithRefreshAhead verifies WithRefreshAhead, previously
+// LRUCache-only, also takes effect for TwoQueueCache.
+func TestTwoQueueWithRefreshAhead(t *testing.T) {
+	var loads int32
+	gc := New(10).
+		EvictType(TYPE_2Q).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			n := atomic.AddInt32(&loads, 1)
+			d := 50 * time.Millisecond
+			return n, &d, nil
+		}).
+		WithRefreshAhead(0.5).
+		Build()
+
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("expected exactly one initial load, got %d", n)
+	}
+
+	time.Sleep(30 * time.Millisecond) // past the 50% refresh-ahead threshold
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the async refresh complete
+	if n := atomic.LoadInt32(&loads); n < 2 {
+		t.Fatalf("expected WithRefreshAhead to trigger a second load, got %d", n)
+	}
+}
@@ -0,0 +1,572 @@
+package gcache
+
+import (
+	"container/list"
+	"io"
+	"runtime"
+	"time"
+)
+
+// Default fractions of the cache size used for the recent (A1in) and ghost
+// (A1out) queues, as described in "2Q: A Low Overhead High Performance Buffer
+// Management Replacement Algorithm" (Johnson & Shasha).
+const (
+	twoQueueRecentRatio = 0.25
+	twoQueueGhostRatio  = 0.5
+)
+
+// TwoQueueCache discards the least recently used items, but gives items that
+// have only been seen once a chance to drain out via a FIFO queue before
+// being promoted into the main LRU. This avoids thrashing the main LRU on
+// scan-heavy access patterns that a pure LRUCache is vulnerable to.
+//
+// TwoQueueCache is a thin wrapper around twoQueueCache so that WithJanitor's
+// finalizer safety net works: the janitor goroutine holds a method value
+// bound to the twoQueueCache, keeping it reachable for as long as the
+// goroutine runs, so a finalizer set on the twoQueueCache itself would never
+// fire. TwoQueueCache holds no reference the goroutine shares, so it becomes
+// collectible as soon as the caller drops it, even with the janitor still
+// running.
+type TwoQueueCache struct {
+	*twoQueueCache
+}
+
+type twoQueueCache struct {
+	baseCache
+	recentSize int
+	ghostSize  int
+
+	items      map[interface{}]*twoQueueItem
+	recentList *list.List
+	mainList   *list.List
+	ghostList  *list.List
+	ghostItems map[interface{}]*list.Element
+	janitor    *janitor
+
+	// loadErr holds the error, if any, returned by the CacheBuilder.LoadFrom
+	// snapshot load newTwoQueueCache performs during construction. Build has
+	// no error return, so LoadErr is the only way a caller can detect a
+	// corrupt snapshot or failing Reader at construction time.
+	loadErr error
+}
+
+type twoQueueItem struct {
+	clock      Clock
+	key        interface{}
+	value      interface{}
+	expiration *time.Time
+	element    *list.Element
+	inMain     bool
+	// ttl is the (possibly jittered) duration expiration was computed from,
+	// kept so refresh-ahead can tell how close to expiry the item is.
+	ttl *time.Duration
+}
+
+// IsExpired returns boolean value whether this item is expired or not.
+func (it *twoQueueItem) IsExpired(now *time.Time) bool {
+	if it.expiration == nil {
+		return false
+	}
+	if now == nil {
+		t := it.clock.Now()
+		now = &t
+	}
+	return it.expiration.Before(*now)
+}
+
+func newTwoQueueCache(cb *CacheBuilder) *TwoQueueCache {
+	c := &twoQueueCache{}
+	buildCache(&c.baseCache, cb)
+
+	c.init()
+	c.loadGroup.cache = c
+
+	wrapper := &TwoQueueCache{c}
+	if c.janitor = startJanitor(cb, c.reapExpired); c.janitor != nil {
+		runtime.SetFinalizer(wrapper, func(w *TwoQueueCache) { w.janitor.Stop() })
+	}
+	if cb.loadFrom != nil {
+		c.loadErr = c.Load(cb.loadFrom)
+	}
+	return wrapper
+}
+
+// reapExpired removes every recent/main item whose TTL has passed as of now.
+// Ghost keys carry no value or expiration and are left alone.
+func (c *twoQueueCache) reapExpired(now time.Time) {
+	c.mu.Lock()
+	var expired []*twoQueueItem
+	for _, item := range c.items {
+		if item.IsExpired(&now) {
+			expired = append(expired, item)
+		}
+	}
+	for _, item := range expired {
+		c.removeItem(item, EvictionExpired)
+		c.publishInvalidation(item.key, InvalidationExpired)
+	}
+	c.mu.Unlock()
+}
+
+// Close stops the background janitor started by CacheBuilder.WithJanitor, if
+// any. It is safe to call even when no janitor was configured.
+//
+// Close is defined on the TwoQueueCache wrapper rather than promoted from
+// twoQueueCache so it can clear the finalizer it was actually registered
+// against; see TwoQueueCache's doc comment.
+func (w *TwoQueueCache) Close() {
+	if w.janitor != nil {
+		w.janitor.Stop()
+		runtime.SetFinalizer(w, nil)
+	}
+}
+
+func (c *twoQueueCache) init() {
+	c.recentSize = int(float64(c.size) * twoQueueRecentRatio)
+	c.ghostSize = int(float64(c.size) * twoQueueGhostRatio)
+	c.items = make(map[interface{}]*twoQueueItem, c.size+1)
+	c.recentList = list.New()
+	c.mainList = list.New()
+	c.ghostList = list.New()
+	c.ghostItems = make(map[interface{}]*list.Element, c.ghostSize+1)
+}
+
+func (c *twoQueueCache) set(key, value interface{}) (interface{}, error) {
+	var err error
+	if c.serializeFunc != nil {
+		value, err = c.serializeFunc(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if item, ok := c.items[key]; ok {
+		oldValue := item.value
+		item.value = value
+		if item.inMain {
+			c.mainList.MoveToFront(item.element)
+		} else {
+			c.recentList.MoveToFront(item.element)
+		}
+		c.setExpiration(item)
+		c.fireEvicted(key, oldValue, EvictionReplaced)
+		if c.addedFunc != nil {
+			c.addedFunc(key, value)
+		}
+		c.fireInsertion(key, value)
+		return item, nil
+	}
+
+	if c.recentList.Len()+c.mainList.Len() >= c.size {
+		c.evict(1)
+	}
+
+	item := &twoQueueItem{clock: c.clock, key: key, value: value}
+	if _, isGhost := c.ghostItems[key]; isGhost {
+		c.removeGhost(key)
+		item.inMain = true
+		item.element = c.mainList.PushFront(item)
+	} else {
+		item.element = c.recentList.PushFront(item)
+	}
+	c.items[key] = item
+	c.setExpiration(item)
+
+	if c.addedFunc != nil {
+		c.addedFunc(key, value)
+	}
+	c.fireInsertion(key, value)
+
+	return item, nil
+}
+
+// setExpiration stamps item with an expiration time jittered per
+// WithExpiryJitter, recording the jittered duration as item.ttl so
+// maybeRefreshAhead can later tell how close to expiry the item is.
+func (c *twoQueueCache) setExpiration(item *twoQueueItem) {
+	if c.expiration != nil {
+		d := c.jitteredDuration(*c.expiration)
+		t := c.clock.Now().Add(d)
+		item.expiration = &t
+		item.ttl = &d
+	}
+}
+
+// maybeRefreshAhead kicks off an asynchronous singleflight reload for key
+// when WithRefreshAhead is configured and item's remaining TTL has dropped
+// below the configured fraction of its original ttl. The reload swaps the
+// value in place via set rather than evicting, so concurrent readers keep
+// serving the stale value until it completes.
+func (c *twoQueueCache) maybeRefreshAhead(key interface{}, item *twoQueueItem) {
+	if c.refreshAheadFraction <= 0 || c.loaderExpireFunc == nil || item.ttl == nil || item.expiration == nil {
+		return
+	}
+	remaining := item.expiration.Sub(c.clock.Now())
+	if remaining > time.Duration(float64(*item.ttl)*c.refreshAheadFraction) {
+		return
+	}
+	go c.getWithLoader(key, false)
+}
+
+// Set a new key-value pair
+func (c *twoQueueCache) Set(key, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.set(key, value)
+	return err
+}
+
+// SetWithExpire sets a new key-value pair with an expiration time
+func (c *twoQueueCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, err := c.set(key, value)
+	if err != nil {
+		return err
+	}
+	d := c.jitteredDuration(expiration)
+	t := c.clock.Now().Add(d)
+	qi := item.(*twoQueueItem)
+	qi.expiration = &t
+	qi.ttl = &d
+	return nil
+}
+
+// Get a value from cache pool using key if it exists.
+// If it does not exists key and has LoaderFunc,
+// generate a value using `LoaderFunc` method returns value.
+func (c *twoQueueCache) Get(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == KeyNotFoundError {
+		return c.getWithLoader(key, true)
+	}
+	return v, err
+}
+
+// GetIFPresent gets a value from cache pool using key if it exists.
+// If it does not exists key, returns KeyNotFoundError.
+// And send a request which refresh value for specified key if cache object has LoaderFunc.
+func (c *twoQueueCache) GetIFPresent(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == KeyNotFoundError {
+		return c.getWithLoader(key, false)
+	}
+	return v, err
+}
+
+func (c *twoQueueCache) get(key interface{}, onLoad bool) (interface{}, error) {
+	v, err := c.getValue(key, onLoad)
+	if err != nil {
+		return nil, err
+	}
+	if c.deserializeFunc != nil {
+		return c.deserializeFunc(key, v)
+	}
+	return v, nil
+}
+
+func (c *twoQueueCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
+	c.mu.Lock()
+	item, ok := c.items[key]
+	if ok {
+		if item.IsExpired(nil) {
+			c.removeItem(item, EvictionExpired)
+			c.publishInvalidation(key, InvalidationExpired)
+			c.mu.Unlock()
+			if !onLoad {
+				c.statsAccessor.IncrMissCount()
+			}
+			return nil, KeyNotFoundError
+		}
+		if item.inMain {
+			c.mainList.MoveToFront(item.element)
+		} else {
+			// Second hit: promote from the recent queue into the main LRU.
+			c.recentList.Remove(item.element)
+			item.inMain = true
+			item.element = c.mainList.PushFront(item)
+		}
+		v := item.value
+		c.maybeRefreshAhead(key, item)
+		c.mu.Unlock()
+		if !onLoad {
+			c.statsAccessor.IncrHitCount()
+		}
+		return v, nil
+	}
+	c.mu.Unlock()
+	if !onLoad {
+		c.statsAccessor.IncrMissCount()
+	}
+	return nil, KeyNotFoundError
+}
+
+func (c *twoQueueCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
+	if c.loaderExpireFunc == nil {
+		return nil, KeyNotFoundError
+	}
+	value, _, err := c.load(key, func(v interface{}, expiration *time.Duration, e error) (interface{}, error) {
+		if e != nil {
+			return nil, e
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		item, err := c.set(key, v)
+		if err != nil {
+			return nil, err
+		}
+		if expiration != nil {
+			d := c.jitteredDuration(*expiration)
+			t := c.clock.Now().Add(d)
+			qi := item.(*twoQueueItem)
+			qi.expiration = &t
+			qi.ttl = &d
+		}
+		return v, nil
+	}, isWait)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// evict removes count items, preferring the recent queue once it has reached
+// its target share so that scanned-once keys don't pollute the main LRU.
+func (c *twoQueueCache) evict(count int) {
+	for i := 0; i < count; i++ {
+		if c.recentList.Len() > 0 && c.recentList.Len() >= c.recentSize {
+			c.evictFromRecent()
+		} else if c.mainList.Len() > 0 {
+			c.evictFromMain()
+		} else if c.recentList.Len() > 0 {
+			c.evictFromRecent()
+		} else {
+			return
+		}
+	}
+}
+
+func (c *twoQueueCache) evictFromRecent() {
+	ele := c.recentList.Back()
+	if ele == nil {
+		return
+	}
+	item := ele.Value.(*twoQueueItem)
+	c.recentList.Remove(ele)
+	delete(c.items, item.key)
+	c.addGhost(item.key)
+	c.fireEvicted(item.key, item.value, EvictionCapacity)
+}
+
+func (c *twoQueueCache) evictFromMain() {
+	ele := c.mainList.Back()
+	if ele == nil {
+		return
+	}
+	item := ele.Value.(*twoQueueItem)
+	c.mainList.Remove(ele)
+	delete(c.items, item.key)
+	c.fireEvicted(item.key, item.value, EvictionCapacity)
+}
+
+func (c *twoQueueCache) addGhost(key interface{}) {
+	if c.ghostList.Len() >= c.ghostSize {
+		back := c.ghostList.Back()
+		if back != nil {
+			c.ghostList.Remove(back)
+			delete(c.ghostItems, back.Value)
+		}
+	}
+	ele := c.ghostList.PushFront(key)
+	c.ghostItems[key] = ele
+}
+
+func (c *twoQueueCache) removeGhost(key interface{}) {
+	if ele, ok := c.ghostItems[key]; ok {
+		c.ghostList.Remove(ele)
+		delete(c.ghostItems, key)
+	}
+}
+
+func (c *twoQueueCache) removeItem(item *twoQueueItem, reason EvictionReason) {
+	if item.inMain {
+		c.mainList.Remove(item.element)
+	} else {
+		c.recentList.Remove(item.element)
+	}
+	delete(c.items, item.key)
+	c.fireEvicted(item.key, item.value, reason)
+}
+
+// Has checks if key exists in cache
+func (c *twoQueueCache) Has(key interface{}) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	return c.has(key, &now)
+}
+
+func (c *twoQueueCache) has(key interface{}, now *time.Time) bool {
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	return !item.IsExpired(now)
+}
+
+// Remove removes the provided key from the cache.
+func (c *twoQueueCache) Remove(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.removeItem(item, EvictionManual)
+	c.publishInvalidation(key, InvalidationRemove)
+	return true
+}
+
+// Invalidate drops key from the local recent/main lists without publishing
+// another InvalidationEvent. Use it to apply an event a cluster transport
+// delivered from a peer node.
+func (c *twoQueueCache) Invalidate(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.removeItem(item, EvictionManual)
+	return true
+}
+
+// GetALL returns all key-value pairs in the cache.
+func (c *twoQueueCache) GetALL(checkExpired bool) map[interface{}]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	items := make(map[interface{}]interface{}, len(c.items))
+	now := time.Now()
+	for k, item := range c.items {
+		if !checkExpired || !item.IsExpired(&now) {
+			items[k] = item.value
+		}
+	}
+	return items
+}
+
+// Keys returns a slice of the keys in the cache.
+func (c *twoQueueCache) Keys(checkExpired bool) []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]interface{}, 0, len(c.items))
+	now := time.Now()
+	for k := range c.items {
+		if !checkExpired || c.has(k, &now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *twoQueueCache) Len(checkExpired bool) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !checkExpired {
+		return len(c.items)
+	}
+	var length int
+	now := time.Now()
+	for k := range c.items {
+		if c.has(k, &now) {
+			length++
+		}
+	}
+	return length
+}
+
+// Purge completely clears the cache, including the ghost queue. Instead of
+// walking every item to fire purgeVisitorFunc under the lock, it swaps in a
+// fresh items map and lists, so Purge latency no longer scales with cache
+// size. If purgeVisitorFunc (or OnEvictedWithReason or a
+// WithInvalidationPublisher) is set, it is dispatched over the discarded map
+// from a background goroutine.
+func (c *twoQueueCache) Purge() {
+	c.mu.Lock()
+	oldItems := c.items
+	c.init()
+	c.mu.Unlock()
+
+	if c.purgeVisitorFunc != nil || c.evictedWithReasonFunc != nil || c.invalidationPublisher != nil {
+		go func() {
+			for key, item := range oldItems {
+				if c.purgeVisitorFunc != nil {
+					c.purgeVisitorFunc(key, item.value)
+				}
+				if c.evictedWithReasonFunc != nil {
+					c.evictedWithReasonFunc(key, item.value, EvictionManual)
+				}
+				c.publishInvalidation(key, InvalidationPurge)
+			}
+		}()
+	}
+}
+
+// Save serializes the cache's key/value pairs, together with their
+// expiration times, to w using encoding/gob, recent queue then main queue,
+// least to most recently used within each, so Load can restore that order.
+// Ghost keys carry no value or expiration and are not persisted.
+func (c *twoQueueCache) Save(w io.Writer) error {
+	c.mu.RLock()
+	entries := make([]persistedEntry, 0, c.recentList.Len()+c.mainList.Len())
+	for e := c.recentList.Back(); e != nil; e = e.Prev() {
+		item := e.Value.(*twoQueueItem)
+		entries = append(entries, persistedEntry{Key: item.key, Value: item.value, Expiration: item.expiration})
+	}
+	for e := c.mainList.Back(); e != nil; e = e.Prev() {
+		item := e.Value.(*twoQueueItem)
+		entries = append(entries, persistedEntry{Key: item.key, Value: item.value, Expiration: item.expiration})
+	}
+	c.mu.RUnlock()
+	return c.encodeSnapshot(w, entries)
+}
+
+// Load restores key/value pairs previously written by Save, skipping any
+// whose expiration has already passed. It does not Purge first, so loading
+// into a non-empty cache merges the two. Every restored entry enters the
+// recent queue, same as any other brand-new key.
+func (c *twoQueueCache) Load(r io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.decodeSnapshot(r, func(entry persistedEntry) {
+		item, err := c.set(entry.Key, entry.Value)
+		if err != nil {
+			return
+		}
+		qi := item.(*twoQueueItem)
+		qi.expiration = entry.Expiration
+		if entry.Expiration != nil {
+			ttl := entry.Expiration.Sub(c.clock.Now())
+			qi.ttl = &ttl
+		}
+	})
+}
+
+// SaveFile writes a Save snapshot to path, replacing it atomically so a
+// concurrent reader never observes a partial file.
+func (c *twoQueueCache) SaveFile(path string) error {
+	return saveToFile(path, c.Save)
+}
+
+// LoadFile restores a snapshot previously written by SaveFile or Save.
+func (c *twoQueueCache) LoadFile(path string) error {
+	return loadFromFile(path, c.Load)
+}
+
+// LoadErr returns the error, if any, from the CacheBuilder.LoadFrom snapshot
+// load performed while building this cache. It is nil when LoadFrom wasn't
+// used or the load succeeded.
+func (c *twoQueueCache) LoadErr() error {
+	return c.loadErr
+}
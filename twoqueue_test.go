@@ -0,0 +1,105 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test2QGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_2Q, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func Test2QLength(t *testing.T) {
+	gc := buildTestLoadingCache(t, TYPE_2Q, 1000, loader)
+	gc.Get("test1")
+	gc.Get("test2")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", length, expectedLength)
+	}
+}
+
+func Test2QEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := 11
+	gc := buildTestLoadingCache(t, TYPE_2Q, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func Test2QGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_2Q)
+}
+
+func Test2QHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_2Q, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+// Test2QGhostPromotion verifies that re-setting a key that was evicted from
+// the recent FIFO into the ghost list is promoted straight into the main
+// LRU rather than treated as a brand new recent entry, per the 2Q algorithm.
+func Test2QGhostPromotion(t *testing.T) {
+	size := 8
+	gc := buildTestCache(t, TYPE_2Q, size)
+
+	for i := 0; i < size*3; i++ {
+		if err := gc.Set(fmt.Sprintf("key-%d", i), i); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// key-0 .. key-15 were evicted from the recent FIFO, but the ghost list
+	// only keeps the last ghostSize (4) of them: key-12 .. key-15.
+	ghosted := fmt.Sprintf("key-%d", size*2-1)
+	if gc.Has(ghosted) {
+		t.Fatalf("expected %s to have been evicted before the ghost re-set", ghosted)
+	}
+	if err := gc.Set(ghosted, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !gc.Has(ghosted) {
+		t.Fatalf("expected %s to be resident again after a ghost-list hit", ghosted)
+	}
+	if item := gc.(*TwoQueueCache).items[ghosted]; item == nil || !item.inMain {
+		t.Fatalf("expected %s to be promoted into the main LRU after a ghost-list hit", ghosted)
+	}
+}